@@ -0,0 +1,71 @@
+package fetch
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ChecksumManifest maps a URL to its expected SHA-256 checksum.
+type ChecksumManifest map[string]string
+
+// LoadChecksumManifest reads a sidecar checksum file, one
+// "<sha256>  <url>" entry per line (the format `sha256sum` produces),
+// mapping each URL to its expected digest.
+func LoadChecksumManifest(path string) (ChecksumManifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("fetch: open checksum manifest %s: %w", path, err)
+	}
+	defer f.Close()
+
+	manifest := make(ChecksumManifest)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("fetch: malformed checksum line %q in %s", line, path)
+		}
+		manifest[fields[1]] = strings.ToLower(fields[0])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("fetch: read checksum manifest %s: %w", path, err)
+	}
+	return manifest, nil
+}
+
+// SHA256File returns the hex-encoded SHA-256 digest of the file at path.
+func SHA256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyChecksum confirms the file at path's SHA-256 digest matches
+// expected, returning a descriptive error if it doesn't.
+func verifyChecksum(path, expected string) error {
+	actual, err := SHA256File(path)
+	if err != nil {
+		return fmt.Errorf("fetch: checksum %s: %w", path, err)
+	}
+	if !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("fetch: checksum mismatch for %s: expected %s, got %s", path, expected, actual)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Retry tuning: exponential backoff with jitter, capped, honoring any
+// Retry-After the server sends.
+const (
+	retryBaseDelay   = 500 * time.Millisecond
+	retryMaxDelay    = 30 * time.Second
+	retryMaxAttempts = 6
+)
+
+// ErrMaxBytesExceeded is returned when a download would exceed
+// Config.MaxBytes.
+var ErrMaxBytesExceeded = errors.New("fetch: response exceeds configured max-bytes")
+
+// retryableError marks a download failure as transient.
+type retryableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+func isRetryable(err error) bool {
+	var rErr *retryableError
+	return errors.As(err, &rErr)
+}
+
+func retryAfterOf(err error) time.Duration {
+	var rErr *retryableError
+	if errors.As(err, &rErr) {
+		return rErr.retryAfter
+	}
+	return 0
+}
+
+// Download fetches rawURL to localFilePath, resuming a partial download if
+// one already exists on disk, retrying transient failures (network errors
+// and HTTP 429/5xx) with exponential backoff and jitter, and, if
+// expectedSHA256 is non-empty, verifying the finished file's checksum.
+func (d *SafeDownloader) Download(ctx context.Context, rawURL, localFilePath, expectedSHA256 string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("fetch: invalid URL %q: %w", rawURL, err)
+	}
+	if err := d.validateURL(ctx, u); err != nil {
+		return err
+	}
+	host := u.Hostname()
+	start := time.Now()
+
+	var lastErr error
+	var nonRetryable bool
+	for attempt := 1; attempt <= retryMaxAttempts; attempt++ {
+		if m := d.cfg.Metrics; m != nil {
+			m.DownloadAttempts.WithLabelValues(host).Inc()
+		}
+		err := d.attemptDownload(ctx, rawURL, localFilePath)
+		if err == nil {
+			lastErr = nil
+			break
+		}
+		lastErr = err
+		if !isRetryable(err) {
+			nonRetryable = true
+			break
+		}
+		if attempt == retryMaxAttempts {
+			break
+		}
+
+		delay := retryAfterOf(err)
+		if delay <= 0 {
+			delay = backoffDelay(attempt)
+		}
+		d.cfg.Logger.Warn("fetch: retrying download", "url", rawURL, "attempt", attempt, "max_attempts", retryMaxAttempts, "delay_ms", delay.Milliseconds(), "error", err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if m := d.cfg.Metrics; m != nil {
+		m.DownloadDuration.WithLabelValues(host).Observe(time.Since(start).Seconds())
+	}
+	if lastErr != nil {
+		if m := d.cfg.Metrics; m != nil {
+			m.DownloadFailures.WithLabelValues(host).Inc()
+		}
+		if nonRetryable {
+			return lastErr
+		}
+		return fmt.Errorf("fetch: %s failed after %d attempts: %w", rawURL, retryMaxAttempts, lastErr)
+	}
+
+	if expectedSHA256 != "" {
+		if err := verifyChecksum(localFilePath, expectedSHA256); err != nil {
+			return err
+		}
+	}
+	if m := d.cfg.Metrics; m != nil {
+		if info, statErr := os.Stat(localFilePath); statErr == nil {
+			m.DownloadBytes.WithLabelValues(host).Add(float64(info.Size()))
+		}
+	}
+	return nil
+}
+
+func (d *SafeDownloader) attemptDownload(ctx context.Context, rawURL, localFilePath string) error {
+	var offset int64
+	if info, err := os.Stat(localFilePath); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	requestStart := time.Now()
+	response, err := d.client.Do(req)
+	if err != nil {
+		return &retryableError{err: err}
+	}
+	defer response.Body.Close()
+	if m := d.cfg.Metrics; m != nil {
+		m.DownloadTTFB.WithLabelValues(req.URL.Hostname()).Observe(time.Since(requestStart).Seconds())
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusPartialContent:
+		// proceed to write below
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Local partial file doesn't match the remote anymore; start over.
+		os.Remove(localFilePath)
+		return &retryableError{err: fmt.Errorf("range not satisfiable for %s, restarting", rawURL)}
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return &retryableError{
+			err:        fmt.Errorf("failed to download file. Status code: %d", response.StatusCode),
+			retryAfter: parseRetryAfter(response.Header.Get("Retry-After")),
+		}
+	default:
+		return fmt.Errorf("failed to download file. Status code: %d", response.StatusCode)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY
+	if response.StatusCode == http.StatusPartialContent {
+		flags |= os.O_APPEND
+	} else {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(localFilePath, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	body := io.Reader(response.Body)
+	if d.cfg.MaxBytes > 0 {
+		remaining := d.cfg.MaxBytes - offset
+		if remaining <= 0 {
+			return fmt.Errorf("%w: %s already at %d bytes, max-bytes is %d", ErrMaxBytesExceeded, rawURL, offset, d.cfg.MaxBytes)
+		}
+		body = io.LimitReader(response.Body, remaining+1)
+	}
+
+	written, err := io.Copy(file, body)
+	if err != nil {
+		return &retryableError{err: err}
+	}
+	if d.cfg.MaxBytes > 0 && offset+written > d.cfg.MaxBytes {
+		return fmt.Errorf("%w: %s exceeded %d bytes", ErrMaxBytesExceeded, rawURL, d.cfg.MaxBytes)
+	}
+
+	return nil
+}
+
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// backoffDelay returns the exponential-backoff-with-jitter delay before
+// retry attempt n (1-indexed), capped at retryMaxDelay.
+func backoffDelay(attempt int) time.Duration {
+	delay := retryBaseDelay * time.Duration(uint64(1)<<uint(attempt-1))
+	if delay > retryMaxDelay || delay <= 0 {
+		delay = retryMaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay)/2+1))
+}
@@ -0,0 +1,129 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newServerOn starts an httptest.Server bound to host (e.g. "127.0.0.1" or
+// "127.0.0.2") instead of httptest's default, so redirect tests can exercise
+// two distinct hostnames without touching real DNS.
+func newServerOn(t *testing.T, host string, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	listener, err := net.Listen("tcp", host+":0")
+	if err != nil {
+		t.Skipf("cannot bind %s (sandboxed network?): %v", host, err)
+	}
+	ts := httptest.NewUnstartedServer(handler)
+	ts.Listener = listener
+	ts.Start()
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestValidateURLRejectsHostOutsideAllowlist(t *testing.T) {
+	d := New(Config{AllowedHosts: []string{"data.commoncrawl.org"}, AllowPrivate: true})
+
+	u, err := url.Parse("http://127.0.0.1:1/crawl-data/sample.warc.gz")
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+	if err := d.validateURL(context.Background(), u); err == nil {
+		t.Fatal("validateURL: want error for host outside allowlist, got nil")
+	}
+}
+
+func TestClientRejectsRedirectToDisallowedHost(t *testing.T) {
+	allowed := newServerOn(t, "127.0.0.2", nil)
+	disallowed := newServerOn(t, "127.0.0.3", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("should never be reached"))
+	})
+	allowed.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, disallowed.URL, http.StatusFound)
+	})
+
+	d := New(Config{AllowedHosts: []string{"127.0.0.2"}, AllowPrivate: true})
+	_, err := d.client.Get(allowed.URL)
+	if err == nil {
+		t.Fatal("client.Get: want error for redirect to a disallowed host, got nil")
+	}
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) || !strings.Contains(urlErr.Err.Error(), "not in the allowlist") {
+		t.Fatalf("client.Get: got error %v, want one wrapping the allowlist rejection", err)
+	}
+}
+
+func TestClientCapsRedirectsAtFive(t *testing.T) {
+	var server *httptest.Server
+	server = newServerOn(t, "127.0.0.4", nil)
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, server.URL+r.URL.Path+"x", http.StatusFound)
+	})
+
+	d := New(Config{AllowedHosts: []string{"127.0.0.4"}, AllowPrivate: true})
+	_, err := d.client.Get(server.URL)
+	if err == nil {
+		t.Fatal("client.Get: want error after exceeding the redirect cap, got nil")
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("stopped after %d redirects", maxRedirects)) {
+		t.Fatalf("client.Get: got error %v, want it to mention the %d-redirect cap", err, maxRedirects)
+	}
+}
+
+func TestDialContextRejectsPrivateIPUnlessAllowed(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot bind 127.0.0.1 (sandboxed network?): %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	addr := listener.Addr().String()
+
+	blocked := New(Config{AllowPrivate: false})
+	if _, err := blocked.dialContext(context.Background(), "tcp", addr); err == nil {
+		t.Fatal("dialContext: want error dialing a loopback address with AllowPrivate=false, got nil")
+	}
+
+	allowed := New(Config{AllowPrivate: true})
+	conn, err := allowed.dialContext(context.Background(), "tcp", addr)
+	if err != nil {
+		t.Fatalf("dialContext: unexpected error with AllowPrivate=true: %v", err)
+	}
+	conn.Close()
+}
+
+func TestDownloadAbortsWhenMaxBytesExceeded(t *testing.T) {
+	server := newServerOn(t, "127.0.0.5", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 1024)))
+	})
+
+	d := New(Config{AllowedHosts: []string{"127.0.0.5"}, AllowPrivate: true, MaxBytes: 16})
+
+	localFilePath := filepath.Join(t.TempDir(), "downloaded")
+	err := d.Download(context.Background(), server.URL, localFilePath, "")
+	if err == nil {
+		t.Fatal("Download: want error for a response exceeding MaxBytes, got nil")
+	}
+	if !errors.Is(err, ErrMaxBytesExceeded) {
+		t.Fatalf("Download: got error %v, want it to wrap ErrMaxBytesExceeded", err)
+	}
+
+	os.Remove(localFilePath)
+}
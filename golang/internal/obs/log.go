@@ -0,0 +1,47 @@
+package obs
+
+import (
+	"log/slog"
+	"os"
+)
+
+var defaultLogger = slog.Default()
+
+// NewLogger builds an slog.Logger writing to stderr in "json" or "text"
+// (default) format at level, which is parsed the way slog.Level.UnmarshalText
+// does ("debug", "info", "warn", "error"; invalid or empty falls back to
+// info). debugMode, set from the DEBUG_MODE env var, forces debug level
+// regardless of level, matching the existing DEBUG_MODE behavior elsewhere.
+// The logger returned also becomes the package default returned by Default.
+func NewLogger(format, level string, debugMode bool) *slog.Logger {
+	lvl := parseLevel(level)
+	if debugMode {
+		lvl = slog.LevelDebug
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	if format == "json" {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	}
+
+	logger := slog.New(handler)
+	defaultLogger = logger
+	return logger
+}
+
+func parseLevel(level string) slog.Level {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo
+	}
+	return lvl
+}
+
+// Default returns the most recently built logger, or slog's own package
+// default if NewLogger hasn't been called yet.
+func Default() *slog.Logger {
+	return defaultLogger
+}
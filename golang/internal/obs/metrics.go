@@ -0,0 +1,85 @@
+// Package obs provides the metrics and structured logging used across the
+// fetch/pipeline/sink subsystems: a Prometheus registry exposed over HTTP,
+// and an slog.Logger configured for text or JSON output.
+package obs
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics holds every counter/histogram/gauge the downloader, pipeline, and
+// sinks report against, plus the registry they're all registered on.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	DownloadAttempts *prometheus.CounterVec
+	DownloadFailures *prometheus.CounterVec
+	DownloadBytes    *prometheus.CounterVec
+	DownloadTTFB     *prometheus.HistogramVec
+	DownloadDuration *prometheus.HistogramVec
+	ParseDuration    prometheus.Histogram
+	SinkRecords      *prometheus.CounterVec
+	SinkBytes        *prometheus.CounterVec
+	SinkOpenFiles    prometheus.Gauge
+}
+
+// NewMetrics constructs a Metrics with every series registered on a fresh
+// registry, ready to be served by Serve.
+func NewMetrics() *Metrics {
+	reg := prometheus.NewRegistry()
+
+	m := &Metrics{
+		Registry: reg,
+		DownloadAttempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ccu_download_attempts_total",
+			Help: "Download attempts, including retries, by target host.",
+		}, []string{"host"}),
+		DownloadFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ccu_download_failures_total",
+			Help: "Download attempts that ended in a non-retryable or exhausted-retries error, by target host.",
+		}, []string{"host"}),
+		DownloadBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ccu_download_bytes_total",
+			Help: "Bytes successfully downloaded, by target host.",
+		}, []string{"host"}),
+		DownloadTTFB: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ccu_download_ttfb_seconds",
+			Help:    "Time to the first response byte, by target host.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"host"}),
+		DownloadDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ccu_download_duration_seconds",
+			Help:    "Total time to fully download a URL, including retries, by target host.",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 12),
+		}, []string{"host"}),
+		ParseDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "ccu_parse_record_seconds",
+			Help:    "Time to parse and project a single WARC/WAT/WET record.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		SinkRecords: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ccu_sink_records_total",
+			Help: "Records written, by language partition.",
+		}, []string{"lang"}),
+		SinkBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ccu_sink_bytes_total",
+			Help: "Bytes written, by language partition.",
+		}, []string{"lang"}),
+		SinkOpenFiles: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "ccu_sink_open_files",
+			Help: "Number of shard files currently open for writing.",
+		}),
+	}
+
+	reg.MustRegister(
+		m.DownloadAttempts,
+		m.DownloadFailures,
+		m.DownloadBytes,
+		m.DownloadTTFB,
+		m.DownloadDuration,
+		m.ParseDuration,
+		m.SinkRecords,
+		m.SinkBytes,
+		m.SinkOpenFiles,
+	)
+
+	return m
+}
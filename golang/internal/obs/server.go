@@ -0,0 +1,38 @@
+package obs
+
+import (
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Serve starts an HTTP server on addr exposing /metrics (Prometheus text
+// format) and /healthz (always 200 OK once the process is up). When
+// debugPprof is set, it additionally serves /debug/pprof/* from the same
+// mux, so profiling doesn't require a second listener. It returns
+// immediately; the server runs until the process exits.
+func (m *Metrics) Serve(addr string, debugPprof bool) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+	if debugPprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			// Best-effort: a dead metrics server shouldn't take down ingestion.
+			Default().Error("obs: metrics server stopped", "addr", addr, "error", err)
+		}
+	}()
+	return server
+}
@@ -0,0 +1,92 @@
+package pipeline
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Checkpoint statuses recorded in the checkpoint file.
+const (
+	StatusDone   = "done"
+	StatusFailed = "failed"
+)
+
+// CheckpointEntry is one line of the JSONL checkpoint file, recording the
+// outcome of processing a single URL.
+type CheckpointEntry struct {
+	URL        string `json:"url"`
+	Status     string `json:"status"`
+	Bytes      int64  `json:"bytes"`
+	SHA256     string `json:"sha256"`
+	FinishedAt string `json:"finishedAt"`
+}
+
+// Checkpoint is an append-only JSONL log of completed URLs. Re-running the
+// pipeline against the same checkpoint file skips any URL already recorded
+// with StatusDone.
+type Checkpoint struct {
+	mu   sync.Mutex
+	file *os.File
+	done map[string]CheckpointEntry
+}
+
+// LoadCheckpoint opens (creating if necessary) the checkpoint file at path
+// and replays it to build the set of already-completed URLs.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	done := make(map[string]CheckpointEntry)
+
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var entry CheckpointEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				continue // tolerate a truncated last line from a prior crash
+			}
+			if entry.Status == StatusDone {
+				done[entry.URL] = entry
+			}
+		}
+		f.Close()
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Checkpoint{file: file, done: done}, nil
+}
+
+// IsDone reports whether url already finished successfully in a prior run.
+func (c *Checkpoint) IsDone(url string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.done[url]
+	return ok
+}
+
+// Record appends entry to the checkpoint file.
+func (c *Checkpoint) Record(entry CheckpointEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	encoder := json.NewEncoder(c.file)
+	if err := encoder.Encode(entry); err != nil {
+		return fmt.Errorf("record checkpoint for %s: %w", entry.URL, err)
+	}
+	if entry.Status == StatusDone {
+		c.done[entry.URL] = entry
+	}
+	return nil
+}
+
+// Close closes the underlying checkpoint file.
+func (c *Checkpoint) Close() error {
+	return c.file.Close()
+}
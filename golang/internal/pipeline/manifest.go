@@ -0,0 +1,56 @@
+package pipeline
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// defaultManifestHost is prepended to bare paths (as found in CommonCrawl's
+// warc.paths.gz manifests, e.g. "crawl-data/CC-MAIN-.../segments/.../x.warc.gz").
+const defaultManifestHost = "https://data.commoncrawl.org/"
+
+// LoadManifest reads a list of WARC/WAT/WET URLs from a manifest file, one
+// per line. Manifests compressed with gzip (such as CommonCrawl's
+// warc.paths.gz) are detected by file extension and decompressed
+// transparently. Blank lines and lines starting with '#' are skipped, and
+// bare paths are resolved against data.commoncrawl.org.
+func LoadManifest(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("open gzip manifest %s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	var urls []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, "http://") && !strings.HasPrefix(line, "https://") {
+			line = defaultManifestHost + strings.TrimPrefix(line, "/")
+		}
+		urls = append(urls, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read manifest %s: %w", path, err)
+	}
+
+	return urls, nil
+}
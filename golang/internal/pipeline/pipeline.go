@@ -0,0 +1,119 @@
+// Package pipeline fans a list of WARC/WAT/WET URLs out across a bounded
+// worker pool, tracking completion in a resumable checkpoint file so that
+// re-running a batch skips URLs that already finished.
+package pipeline
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// JobFunc processes a single URL, returning the number of bytes written and
+// its SHA-256 checksum for the checkpoint record.
+type JobFunc func(ctx context.Context, url string) (bytesWritten int64, sha256 string, err error)
+
+// Pipeline runs a JobFunc over a set of URLs using a fixed-size worker pool.
+type Pipeline struct {
+	Workers    int
+	Checkpoint *Checkpoint
+	Logger     *slog.Logger
+}
+
+// New returns a Pipeline with the given worker count (clamped to at least
+// one), an optional checkpoint for resumability, and an optional logger
+// (slog.Default() is used when logger is nil).
+func New(workers int, checkpoint *Checkpoint, logger *slog.Logger) *Pipeline {
+	if workers < 1 {
+		workers = 1
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Pipeline{Workers: workers, Checkpoint: checkpoint, Logger: logger}
+}
+
+// Run feeds urls to the worker pool and blocks until all of them have been
+// processed or ctx is cancelled. It returns the first error encountered, if
+// any, but always lets in-flight workers finish so the checkpoint stays
+// consistent.
+func (p *Pipeline) Run(ctx context.Context, urls []string, process JobFunc) error {
+	jobs := make(chan string)
+	errs := make(chan error)
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.Workers; i++ {
+		wg.Add(1)
+		go p.worker(ctx, jobs, process, &wg, errs)
+	}
+
+	// Drain errs as workers produce them; a worker can fail many jobs (it
+	// loops over jobs, not just once), so errs can't be left buffered and
+	// collected after wg.Wait() without risking every worker blocking on a
+	// full channel forever.
+	var first error
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for err := range errs {
+			if first == nil {
+				first = err
+			}
+		}
+	}()
+
+feed:
+	for _, u := range urls {
+		if p.Checkpoint != nil && p.Checkpoint.IsDone(u) {
+			p.Logger.Debug("pipeline: skipping already-completed URL", "url", u)
+			continue
+		}
+		select {
+		case jobs <- u:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+	<-collected
+
+	return first
+}
+
+func (p *Pipeline) worker(ctx context.Context, jobs <-chan string, process JobFunc, wg *sync.WaitGroup, errs chan<- error) {
+	defer wg.Done()
+
+	for url := range jobs {
+		if ctx.Err() != nil {
+			return
+		}
+
+		start := time.Now()
+		bytesWritten, sum, err := process(ctx, url)
+
+		entry := CheckpointEntry{
+			URL:        url,
+			Bytes:      bytesWritten,
+			SHA256:     sum,
+			FinishedAt: time.Now().UTC().Format(time.RFC3339),
+		}
+		elapsed := time.Since(start)
+		if err != nil {
+			entry.Status = StatusFailed
+			p.Logger.Error("pipeline: job failed", "url", url, "elapsed_ms", elapsed.Milliseconds(), "error", err)
+			errs <- err
+		} else {
+			entry.Status = StatusDone
+			p.Logger.Info("pipeline: job done", "url", url, "elapsed_ms", elapsed.Milliseconds(), "bytes", bytesWritten)
+		}
+
+		if p.Checkpoint != nil {
+			if cErr := p.Checkpoint.Record(entry); cErr != nil {
+				p.Logger.Error("pipeline: failed to record checkpoint", "url", url, "error", cErr)
+			}
+		}
+	}
+}
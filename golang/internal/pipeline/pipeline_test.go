@@ -0,0 +1,92 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRunReturnsWhenEveryJobFails guards against the worker-pool deadlock
+// fixed in this package: with more failing jobs than workers, a worker
+// loops over many jobs (not just one) and used to block forever sending to
+// an under-sized buffered errs channel once every worker had a failure
+// queued up, so Run never returned and never released its worker
+// goroutines.
+func TestRunReturnsWhenEveryJobFails(t *testing.T) {
+	const workers = 3
+	urls := make([]string, workers*10)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("http://example.com/%d", i)
+	}
+
+	p := New(workers, nil, nil)
+	before := runtime.NumGoroutine()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Run(context.Background(), urls, func(ctx context.Context, url string) (int64, string, error) {
+			return 0, "", errors.New("boom")
+		})
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Run() returned a nil error, want the first job's error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return within 5s; worker pool is likely deadlocked on the errs channel")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("goroutine count grew from %d to %d after Run returned; worker or collector goroutines leaked", before, after)
+	}
+}
+
+// TestRunSkipsCheckpointedURLs confirms that a second Run against the same
+// Checkpoint skips every URL the first Run already recorded as done.
+func TestRunSkipsCheckpointedURLs(t *testing.T) {
+	checkpoint, err := LoadCheckpoint(filepath.Join(t.TempDir(), "checkpoint.jsonl"))
+	if err != nil {
+		t.Fatalf("LoadCheckpoint: %v", err)
+	}
+	defer checkpoint.Close()
+
+	p := New(2, checkpoint, nil)
+	urls := []string{"http://example.com/a", "http://example.com/b"}
+
+	recordProcessed := func(processed *[]string, mu *sync.Mutex) JobFunc {
+		return func(ctx context.Context, url string) (int64, string, error) {
+			mu.Lock()
+			*processed = append(*processed, url)
+			mu.Unlock()
+			return 1, "sum", nil
+		}
+	}
+
+	var mu sync.Mutex
+	var firstRun []string
+	if err := p.Run(context.Background(), urls, recordProcessed(&firstRun, &mu)); err != nil {
+		t.Fatalf("first Run: %v", err)
+	}
+	if len(firstRun) != len(urls) {
+		t.Fatalf("first run processed %v, want both URLs", firstRun)
+	}
+
+	var secondRun []string
+	if err := p.Run(context.Background(), urls, recordProcessed(&secondRun, &mu)); err != nil {
+		t.Fatalf("second Run: %v", err)
+	}
+	if len(secondRun) != 0 {
+		t.Fatalf("second run processed %v, want none (both already checkpointed)", secondRun)
+	}
+}
@@ -0,0 +1,242 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"commoncrawl-utilities/internal/obs"
+)
+
+// languageOf extracts the partition key the file/object sinks use to group
+// records: the record's language, defaulting to "eng" when absent.
+func languageOf(record map[string]string) string {
+	language := strings.ReplaceAll(record["WARC-Identified-Content-Language"], ",", "_")
+	if language == "" {
+		language = "eng"
+	}
+	return language
+}
+
+// FileSink writes records to "<dir>/<language>/output.jsonl", one shard
+// per language partition. Each shard is written to a "*.partial" file and
+// atomically renamed to its final name only once it's closed cleanly, so a
+// crash mid-write never leaves a corrupt, half-written output.jsonl behind.
+// If Options.PartMaxBytes is set, a shard rotates to a new numbered file
+// ("output-0001.jsonl", ...) once it crosses that size.
+type FileSink struct {
+	dir         string
+	resume      bool
+	rotateBytes int64
+	metrics     *obs.Metrics
+
+	mu     sync.Mutex
+	shards map[string]*fileShard
+	seqs   map[string]int
+}
+
+type fileShard struct {
+	file        *os.File
+	partialPath string
+	finalPath   string
+	written     int64
+}
+
+// NewFileSink returns a FileSink rooted at dir, configured by opts.
+func NewFileSink(dir string, opts Options) *FileSink {
+	return &FileSink{
+		dir:         dir,
+		resume:      opts.Resume,
+		rotateBytes: opts.PartMaxBytes,
+		metrics:     opts.Metrics,
+		shards:      make(map[string]*fileShard),
+		seqs:        make(map[string]int),
+	}
+}
+
+func (s *FileSink) Write(_ context.Context, record map[string]string) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(record); err != nil {
+		return err
+	}
+	language := languageOf(record)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	shard, err := s.openShardLocked(language)
+	if err != nil {
+		return err
+	}
+
+	n, err := shard.file.Write(buf.Bytes())
+	shard.written += int64(n)
+	if err != nil {
+		return err
+	}
+	if s.metrics != nil {
+		s.metrics.SinkRecords.WithLabelValues(language).Inc()
+		s.metrics.SinkBytes.WithLabelValues(language).Add(float64(n))
+	}
+
+	if s.rotateBytes > 0 && shard.written >= s.rotateBytes {
+		return s.closeShardLocked(language)
+	}
+	return nil
+}
+
+func (s *FileSink) shardName(seq int) string {
+	if s.rotateBytes <= 0 {
+		return "output.jsonl"
+	}
+	return fmt.Sprintf("output-%04d.jsonl", seq)
+}
+
+// recoverSeqLocked inspects dir for numbered shards left behind by a
+// previous run (output-NNNN.jsonl, finalized, and output-NNNN.jsonl.partial,
+// mid-write when the process crashed) and returns the sequence number a
+// -resume run should reopen: the highest in-progress partial if one is
+// ahead of the highest finalized shard, otherwise the next number after the
+// highest finalized shard. Without this, a resumed run would always start
+// back at seq 0 and, on its first rotation, rename its fresh partial over
+// an already-finalized output-0000.jsonl, silently clobbering it.
+func (s *FileSink) recoverSeqLocked(dir string) int {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+
+	maxFinal, maxPartial := -1, -1
+	for _, entry := range entries {
+		name := entry.Name()
+		partial := strings.HasSuffix(name, ".jsonl.partial")
+		final := strings.HasSuffix(name, ".jsonl") && !partial
+		if !partial && !final {
+			continue
+		}
+		base := strings.TrimSuffix(strings.TrimSuffix(name, ".partial"), ".jsonl")
+		seqStr := strings.TrimPrefix(base, "output-")
+		if seqStr == base {
+			continue // "output.jsonl"(.partial): the non-rotating name, no sequence to track
+		}
+		seq, err := strconv.Atoi(seqStr)
+		if err != nil {
+			continue
+		}
+		if partial && seq > maxPartial {
+			maxPartial = seq
+		}
+		if final && seq > maxFinal {
+			maxFinal = seq
+		}
+	}
+
+	if maxPartial > maxFinal {
+		return maxPartial
+	}
+	return maxFinal + 1
+}
+
+func (s *FileSink) openShardLocked(language string) (*fileShard, error) {
+	if shard, ok := s.shards[language]; ok {
+		return shard, nil
+	}
+
+	dir := filepath.Join(s.dir, language)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	seq, ok := s.seqs[language]
+	if !ok {
+		if s.resume {
+			seq = s.recoverSeqLocked(dir)
+		}
+		s.seqs[language] = seq
+	}
+	finalPath := filepath.Join(dir, s.shardName(seq))
+	partialPath := finalPath + ".partial"
+
+	flags := os.O_CREATE | os.O_WRONLY
+	if _, err := os.Stat(partialPath); err == nil && !s.resume {
+		os.Remove(partialPath)
+	}
+	if s.resume {
+		flags |= os.O_APPEND
+	} else {
+		flags |= os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(partialPath, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	var written int64
+	if info, err := f.Stat(); err == nil {
+		written = info.Size()
+	}
+
+	shard := &fileShard{file: f, partialPath: partialPath, finalPath: finalPath, written: written}
+	s.shards[language] = shard
+	if s.metrics != nil {
+		s.metrics.SinkOpenFiles.Inc()
+	}
+	return shard, nil
+}
+
+// closeShardLocked syncs, closes, and renames partitionKey's shard into
+// place, then advances its sequence number so the next write opens a fresh
+// shard.
+func (s *FileSink) closeShardLocked(partitionKey string) error {
+	shard, ok := s.shards[partitionKey]
+	if !ok {
+		return nil
+	}
+	delete(s.shards, partitionKey)
+	s.seqs[partitionKey]++
+	if s.metrics != nil {
+		s.metrics.SinkOpenFiles.Dec()
+	}
+
+	if err := shard.file.Sync(); err != nil {
+		shard.file.Close()
+		return fmt.Errorf("sink: sync %s: %w", shard.partialPath, err)
+	}
+	if err := shard.file.Close(); err != nil {
+		return fmt.Errorf("sink: close %s: %w", shard.partialPath, err)
+	}
+	if err := os.Rename(shard.partialPath, shard.finalPath); err != nil {
+		return fmt.Errorf("sink: rename %s to %s: %w", shard.partialPath, shard.finalPath, err)
+	}
+	return nil
+}
+
+// Rotate closes out the current shard for partitionKey so the next write
+// starts a fresh one, independent of -rotate-bytes.
+func (s *FileSink) Rotate(partitionKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeShardLocked(partitionKey)
+}
+
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var first error
+	for language := range s.shards {
+		if err := s.closeShardLocked(language); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
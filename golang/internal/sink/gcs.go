@@ -0,0 +1,44 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"cloud.google.com/go/storage"
+)
+
+// gcsUploader uploads parts to Google Cloud Storage using the client
+// library's resumable Writer, which handles chunking internally.
+type gcsUploader struct {
+	bucket string
+	client *storage.Client
+}
+
+func newGCSUploader(ctx context.Context, bucket string) (*gcsUploader, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create GCS client: %w", err)
+	}
+	return &gcsUploader{bucket: bucket, client: client}, nil
+}
+
+func (u *gcsUploader) Upload(ctx context.Context, key string, body []byte) error {
+	w := u.client.Bucket(u.bucket).Object(key).NewWriter(ctx)
+	if _, err := io.Copy(w, bytes.NewReader(body)); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// NewGCSSink returns a RecordSink that buffers records per language and
+// uploads each finished part to gs://bucket/prefix/lang=.../part-NNNN.jsonl.{gz,zst}.
+func NewGCSSink(bucket, prefix string, opts Options) (RecordSink, error) {
+	uploader, err := newGCSUploader(context.Background(), bucket)
+	if err != nil {
+		return nil, fmt.Errorf("gcs sink: %w", err)
+	}
+	return newObjectSink(prefix, opts, uploader), nil
+}
@@ -0,0 +1,58 @@
+package sink
+
+import (
+	"context"
+	"sync"
+)
+
+// MockSink records every write in memory instead of touching a filesystem
+// or cloud bucket. It exists so the pipeline wiring can be exercised
+// end-to-end (in tests or local dry runs) without real cloud credentials.
+type MockSink struct {
+	mu      sync.Mutex
+	records []map[string]string
+	rotated []string
+	closed  bool
+}
+
+// NewMockSink returns an empty MockSink.
+func NewMockSink() *MockSink {
+	return &MockSink{}
+}
+
+func (s *MockSink) Write(_ context.Context, record map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+func (s *MockSink) Rotate(partitionKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rotated = append(s.rotated, partitionKey)
+	return nil
+}
+
+func (s *MockSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+// Records returns a copy of every record written so far.
+func (s *MockSink) Records() []map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]map[string]string, len(s.records))
+	copy(out, s.records)
+	return out
+}
+
+// Closed reports whether Close has been called.
+func (s *MockSink) Closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
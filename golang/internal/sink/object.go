@@ -0,0 +1,152 @@
+package sink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// objectUploader is the minimal operation an object-storage backend (S3,
+// GCS, ...) needs to support for objectSink to buffer and ship parts.
+type objectUploader interface {
+	Upload(ctx context.Context, key string, body []byte) error
+}
+
+// objectSink buffers records per language partition into compressed parts
+// and flushes each part to an objectUploader once it crosses a size or age
+// threshold. It backs both the S3 and GCS sinks.
+type objectSink struct {
+	prefix   string
+	opts     Options
+	uploader objectUploader
+
+	mu    sync.Mutex
+	parts map[string]*part
+	seqs  map[string]int
+}
+
+type part struct {
+	buf    bytes.Buffer
+	cw     io.WriteCloser
+	opened time.Time
+}
+
+func newObjectSink(prefix string, opts Options, uploader objectUploader) *objectSink {
+	return &objectSink{
+		prefix:   prefix,
+		opts:     opts,
+		uploader: uploader,
+		parts:    make(map[string]*part),
+		seqs:     make(map[string]int),
+	}
+}
+
+func (s *objectSink) Write(ctx context.Context, record map[string]string) error {
+	language := languageOf(record)
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("objectSink: marshal record: %w", err)
+	}
+	payload = append(payload, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, err := s.openPartLocked(language)
+	if err != nil {
+		return err
+	}
+	if _, err := p.cw.Write(payload); err != nil {
+		return fmt.Errorf("objectSink: compress record: %w", err)
+	}
+
+	if int64(p.buf.Len()) >= s.opts.PartMaxBytes || time.Since(p.opened) >= s.opts.PartMaxAge {
+		return s.flushLocked(ctx, language)
+	}
+	return nil
+}
+
+func (s *objectSink) openPartLocked(language string) (*part, error) {
+	if p, ok := s.parts[language]; ok {
+		return p, nil
+	}
+
+	p := &part{opened: time.Now()}
+	cw, err := newCompressWriter(&p.buf, s.opts.Compression)
+	if err != nil {
+		return nil, fmt.Errorf("objectSink: new %s writer: %w", s.opts.Compression, err)
+	}
+	p.cw = cw
+	s.parts[language] = p
+	return p, nil
+}
+
+// Rotate force-flushes the part currently open for partitionKey,
+// independent of its size or age.
+func (s *objectSink) Rotate(partitionKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flushLocked(context.Background(), partitionKey)
+}
+
+func (s *objectSink) flushLocked(ctx context.Context, language string) error {
+	p, ok := s.parts[language]
+	if !ok {
+		return nil
+	}
+	delete(s.parts, language)
+
+	if err := p.cw.Close(); err != nil {
+		return fmt.Errorf("objectSink: close compressor for %s: %w", language, err)
+	}
+	if p.buf.Len() == 0 {
+		return nil
+	}
+
+	seq := s.seqs[language]
+	s.seqs[language] = seq + 1
+
+	key := fmt.Sprintf("%s/lang=%s/part-%04d.jsonl.%s", s.prefix, language, seq, compressExt(s.opts.Compression))
+	if err := s.uploader.Upload(ctx, key, p.buf.Bytes()); err != nil {
+		return fmt.Errorf("objectSink: upload %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *objectSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var first error
+	for language := range s.parts {
+		if err := s.flushLocked(context.Background(), language); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func newCompressWriter(w io.Writer, kind string) (io.WriteCloser, error) {
+	switch kind {
+	case "zstd":
+		return zstd.NewWriter(w)
+	case "gzip", "":
+		return gzip.NewWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unknown compression %q", kind)
+	}
+}
+
+func compressExt(kind string) string {
+	if kind == "zstd" {
+		return "zst"
+	}
+	return "gz"
+}
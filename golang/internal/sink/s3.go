@@ -0,0 +1,47 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// s3Uploader uploads parts to S3 via the SDK's multipart manager, so parts
+// larger than the single-PUT limit still upload in one call.
+type s3Uploader struct {
+	bucket   string
+	uploader *manager.Uploader
+}
+
+func newS3Uploader(ctx context.Context, bucket string) (*s3Uploader, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load AWS config: %w", err)
+	}
+	client := s3.NewFromConfig(cfg)
+	return &s3Uploader{bucket: bucket, uploader: manager.NewUploader(client)}, nil
+}
+
+func (u *s3Uploader) Upload(ctx context.Context, key string, body []byte) error {
+	_, err := u.uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(u.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(body),
+	})
+	return err
+}
+
+// NewS3Sink returns a RecordSink that buffers records per language and
+// uploads each finished part to s3://bucket/prefix/lang=.../part-NNNN.jsonl.{gz,zst}.
+func NewS3Sink(bucket, prefix string, opts Options) (RecordSink, error) {
+	uploader, err := newS3Uploader(context.Background(), bucket)
+	if err != nil {
+		return nil, fmt.Errorf("s3 sink: %w", err)
+	}
+	return newObjectSink(prefix, opts, uploader), nil
+}
@@ -0,0 +1,100 @@
+// Package sink provides pluggable destinations for processed WARC/WAT/WET
+// records. A RecordSink decides how records are partitioned, buffered, and
+// persisted, so the ingestion pipeline can write to a local directory, to
+// cloud object storage, or to stdout without changing its own logic.
+package sink
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"commoncrawl-utilities/internal/obs"
+)
+
+// RecordSink is the output destination for processed records.
+// Implementations are responsible for their own partitioning (typically by
+// detected language), buffering, and durability.
+type RecordSink interface {
+	// Write appends record to whichever partition the sink derives from it.
+	Write(ctx context.Context, record map[string]string) error
+	// Rotate closes out the current part for partitionKey and starts a new
+	// one, independent of the sink's own size/time thresholds.
+	Rotate(partitionKey string) error
+	// Close flushes and releases all resources held by the sink.
+	Close() error
+}
+
+// Options configures the size/time bounds and compression used by sinks
+// that buffer records into parts, and the crash-recovery behavior of the
+// file sink.
+type Options struct {
+	// PartMaxBytes closes and uploads a part once its buffered, compressed
+	// size would exceed this many bytes (object sinks; defaults to 64 MiB
+	// there) or rotates the file sink to a new numbered shard once its
+	// uncompressed size exceeds this many bytes (0 disables rotation).
+	PartMaxBytes int64
+	// PartMaxAge closes and uploads a part this long after it was opened,
+	// even if PartMaxBytes hasn't been reached. Object sinks only;
+	// defaults to 5 minutes.
+	PartMaxAge time.Duration
+	// Compression is "gzip" (default) or "zstd". Object sinks only.
+	Compression string
+	// Resume reopens an existing "*.partial" file left behind by a
+	// previous crashed run instead of discarding it. File sink only.
+	Resume bool
+	// Metrics, if set, records per-language record/byte counters and the
+	// open-file-handle gauge (file sink only).
+	Metrics *obs.Metrics
+}
+
+func (o Options) withDefaults() Options {
+	if o.PartMaxBytes <= 0 {
+		o.PartMaxBytes = 64 * 1024 * 1024
+	}
+	if o.PartMaxAge <= 0 {
+		o.PartMaxAge = 5 * time.Minute
+	}
+	if o.Compression == "" {
+		o.Compression = "gzip"
+	}
+	return o
+}
+
+// New constructs a RecordSink for uri. Supported schemes are "file://"
+// (the historical local-directory behavior), "s3://bucket/prefix",
+// "gs://bucket/prefix", the bare string "stdout", and the bare string
+// "mock" (an in-memory sink for tests and dry runs).
+func New(uri string, opts Options) (RecordSink, error) {
+	if uri == "stdout" {
+		return NewStdoutSink(), nil
+	}
+	if uri == "mock" {
+		return NewMockSink(), nil
+	}
+
+	scheme, rest, ok := strings.Cut(uri, "://")
+	if !ok {
+		return nil, fmt.Errorf("sink: %q is not a valid sink URI (expected scheme://...)", uri)
+	}
+
+	switch scheme {
+	case "file":
+		return NewFileSink(rest, opts), nil
+	case "s3":
+		bucket, prefix := splitBucketPrefix(rest)
+		return NewS3Sink(bucket, prefix, opts.withDefaults())
+	case "gs":
+		bucket, prefix := splitBucketPrefix(rest)
+		return NewGCSSink(bucket, prefix, opts.withDefaults())
+	default:
+		return nil, fmt.Errorf("sink: unsupported scheme %q in %q", scheme, uri)
+	}
+}
+
+// splitBucketPrefix splits "bucket/prefix/parts" into ("bucket", "prefix/parts").
+func splitBucketPrefix(rest string) (bucket, prefix string) {
+	bucket, prefix, _ = strings.Cut(rest, "/")
+	return bucket, prefix
+}
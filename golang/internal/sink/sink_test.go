@@ -0,0 +1,179 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewMockSink(t *testing.T) {
+	s, err := New("mock", Options{})
+	if err != nil {
+		t.Fatalf("New(\"mock\", ...): %v", err)
+	}
+	mock, ok := s.(*MockSink)
+	if !ok {
+		t.Fatalf("New(\"mock\", ...) returned %T, want *MockSink", s)
+	}
+
+	record := map[string]string{"WARC-Record-ID": "1", "data": "hello"}
+	if err := mock.Write(context.Background(), record); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := mock.Rotate("eng"); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if err := mock.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	records := mock.Records()
+	if len(records) != 1 || records[0]["data"] != "hello" {
+		t.Fatalf("Records() = %v, want one record with data=hello", records)
+	}
+	if !mock.Closed() {
+		t.Fatal("Closed() = false after Close()")
+	}
+}
+
+func TestFileSinkRotation(t *testing.T) {
+	dir := t.TempDir()
+	s := NewFileSink(dir, Options{PartMaxBytes: 1})
+
+	for i := 0; i < 3; i++ {
+		if err := s.Write(context.Background(), map[string]string{"data": "x"}); err != nil {
+			t.Fatalf("Write #%d: %v", i, err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	for _, name := range []string{"output-0000.jsonl", "output-0001.jsonl", "output-0002.jsonl"} {
+		path := filepath.Join(dir, "eng", name)
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected shard %s to exist: %v", path, err)
+		}
+	}
+}
+
+func TestFileSinkResume(t *testing.T) {
+	dir := t.TempDir()
+
+	s := NewFileSink(dir, Options{Resume: true})
+	if err := s.Write(context.Background(), map[string]string{"data": "first"}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	// Simulate a crash: the shard is never Close()'d, so it's left behind
+	// as "output.jsonl.partial" instead of being renamed into place.
+	partialPath := filepath.Join(dir, "eng", "output.jsonl.partial")
+	if _, err := os.Stat(partialPath); err != nil {
+		t.Fatalf("expected partial shard %s to exist: %v", partialPath, err)
+	}
+
+	resumed := NewFileSink(dir, Options{Resume: true})
+	if err := resumed.Write(context.Background(), map[string]string{"data": "second"}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := resumed.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	finalPath := filepath.Join(dir, "eng", "output.jsonl")
+	f, err := os.Open(finalPath)
+	if err != nil {
+		t.Fatalf("open %s: %v", finalPath, err)
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 2 {
+		t.Fatalf("resumed shard has %d lines, want 2 (preserved first write plus the resumed second): %v", len(lines), lines)
+	}
+}
+
+func TestFileSinkResumeRecoversSeqAfterRotation(t *testing.T) {
+	dir := t.TempDir()
+
+	// First run: rotate-bytes=1 forces a rotation after every write, so
+	// "eng/output-0000.jsonl" is fully committed, and the crash leaves
+	// "eng/output-0001.jsonl.partial" mid-write.
+	first := NewFileSink(dir, Options{PartMaxBytes: 1, Resume: true})
+	if err := first.Write(context.Background(), map[string]string{"data": "committed"}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := first.Write(context.Background(), map[string]string{"data": "in-progress"}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	// Simulate the crash: never call Close, so output-0001.jsonl.partial
+	// is left behind instead of being renamed.
+
+	committedPath := filepath.Join(dir, "eng", "output-0000.jsonl")
+	committedBefore, err := os.ReadFile(committedPath)
+	if err != nil {
+		t.Fatalf("read %s: %v", committedPath, err)
+	}
+
+	// Resume: must continue output-0001.jsonl.partial, not reopen
+	// output-0000.jsonl.partial and rename it over the committed shard.
+	resumed := NewFileSink(dir, Options{PartMaxBytes: 1, Resume: true})
+	if err := resumed.Write(context.Background(), map[string]string{"data": "resumed"}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := resumed.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	committedAfter, err := os.ReadFile(committedPath)
+	if err != nil {
+		t.Fatalf("read %s: %v", committedPath, err)
+	}
+	if string(committedBefore) != string(committedAfter) {
+		t.Fatalf("resume clobbered the already-committed shard: before=%q after=%q", committedBefore, committedAfter)
+	}
+
+	nextPath := filepath.Join(dir, "eng", "output-0001.jsonl")
+	if _, err := os.Stat(nextPath); err != nil {
+		t.Errorf("expected %s to exist after resume: %v", nextPath, err)
+	}
+}
+
+func TestFileSinkDiscardsStalePartialWithoutResume(t *testing.T) {
+	dir := t.TempDir()
+
+	s := NewFileSink(dir, Options{})
+	if err := s.Write(context.Background(), map[string]string{"data": "stale"}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	// Leave the shard open (uncommitted), then start a fresh sink without
+	// -resume: it should discard the stale partial rather than append.
+	fresh := NewFileSink(dir, Options{})
+	if err := fresh.Write(context.Background(), map[string]string{"data": "fresh"}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := fresh.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	finalPath := filepath.Join(dir, "eng", "output.jsonl")
+	f, err := os.Open(finalPath)
+	if err != nil {
+		t.Fatalf("open %s: %v", finalPath, err)
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 1 {
+		t.Fatalf("shard has %d lines, want 1 (stale partial discarded): %v", len(lines), lines)
+	}
+}
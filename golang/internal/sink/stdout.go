@@ -0,0 +1,32 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// StdoutSink writes every record as a JSON line to os.Stdout, ignoring
+// partitioning. Useful for piping output straight into another process.
+type StdoutSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewStdoutSink returns a sink that writes to os.Stdout.
+func NewStdoutSink() *StdoutSink {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	return &StdoutSink{enc: enc}
+}
+
+func (s *StdoutSink) Write(_ context.Context, record map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(record)
+}
+
+func (s *StdoutSink) Rotate(string) error { return nil }
+
+func (s *StdoutSink) Close() error { return nil }
@@ -0,0 +1,31 @@
+package warc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/textproto"
+)
+
+// SplitHTTPPayload parses the embedded HTTP request or response that forms
+// the block of a WARC "request"/"response" record, returning its start
+// line (e.g. "GET / HTTP/1.1" or "HTTP/1.1 200 OK"), its HTTP headers, and
+// a reader positioned at the payload that follows them. WAT/WET records
+// (WARC-Type "metadata"/"conversion") carry no embedded HTTP message and
+// should be read directly from Record.Body instead.
+func SplitHTTPPayload(body io.Reader) (startLine string, header textproto.MIMEHeader, payload io.Reader, err error) {
+	br := bufio.NewReader(body)
+	tp := textproto.NewReader(br)
+
+	startLine, err = tp.ReadLine()
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("warc: read HTTP start line: %w", err)
+	}
+
+	header, err = tp.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return "", nil, nil, fmt.Errorf("warc: read HTTP headers: %w", err)
+	}
+
+	return startLine, header, br, nil
+}
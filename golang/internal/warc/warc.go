@@ -0,0 +1,109 @@
+// Package warc implements a minimal reader for the WARC 1.1 file format
+// (https://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/)
+// used for CommonCrawl's .warc, .wat, and .wet exports.
+package warc
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// Record is a single parsed WARC record.
+type Record struct {
+	// Version is the record's version line, e.g. "WARC/1.1".
+	Version string
+	// Header holds the record's WARC-Type, WARC-Record-ID, Content-Length,
+	// and any other "Key: value" header field.
+	Header textproto.MIMEHeader
+	// Body is the record's block, bounded to exactly Content-Length bytes.
+	// It must be fully read (or Next called again, which discards the
+	// remainder) before moving on to the next record.
+	Body io.Reader
+}
+
+// Reader parses a sequence of back-to-back WARC records from an
+// uncompressed byte stream.
+type Reader struct {
+	br      *bufio.Reader
+	current *io.LimitedReader
+}
+
+// NewReader returns a Reader over r, which must yield raw (already
+// decompressed) WARC record data. Use NewGzipReader for the gzip-compressed
+// files CommonCrawl actually distributes.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{br: bufio.NewReader(r)}
+}
+
+// NewGzipReader wraps a gzip-compressed WARC stream and returns a Reader
+// over its decompressed records. CommonCrawl WARC files are a sequence of
+// independently gzip-compressed members, one per record; compress/gzip's
+// default multistream mode concatenates them transparently.
+func NewGzipReader(r io.Reader) (*Reader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("warc: open gzip stream: %w", err)
+	}
+	gz.Multistream(true)
+	return NewReader(gz), nil
+}
+
+// Next parses and returns the next record. At EOF it returns io.EOF.
+//
+// Callers do not need to drain a Record's Body themselves: the next call
+// to Next discards whatever remains of the previous record's body before
+// parsing continues.
+func (r *Reader) Next() (*Record, error) {
+	if r.current != nil {
+		if _, err := io.Copy(io.Discard, r.current); err != nil {
+			return nil, fmt.Errorf("warc: drain previous record body: %w", err)
+		}
+		r.current = nil
+	}
+
+	version, err := r.readVersionLine()
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := textproto.NewReader(r.br).ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("warc: read headers: %w", err)
+	}
+
+	contentLength, err := strconv.ParseInt(strings.TrimSpace(header.Get("Content-Length")), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("warc: invalid or missing Content-Length: %w", err)
+	}
+
+	r.current = &io.LimitedReader{R: r.br, N: contentLength}
+	return &Record{Version: version, Header: header, Body: r.current}, nil
+}
+
+// readVersionLine reads past any blank lines trailing the previous record
+// (WARC separates records with a CRLF CRLF after each block) and returns
+// the "WARC/1.x" version line that starts the next one.
+func (r *Reader) readVersionLine() (string, error) {
+	for {
+		line, err := r.br.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && strings.TrimSpace(line) == "" {
+				return "", io.EOF
+			}
+			return "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "WARC/") {
+			return "", fmt.Errorf("warc: expected WARC version line, got %q", line)
+		}
+		return line, nil
+	}
+}
@@ -0,0 +1,134 @@
+package warc
+
+import (
+	"io"
+	"os"
+	"testing"
+)
+
+// TestReaderNext exercises Reader.Next against testdata/sample.warc, a
+// small two-record fixture (one "response" record with an embedded HTTP
+// payload, one "metadata" record) covering header parsing, Content-Length
+// bounding of the body, and discarding the previous record's unread
+// remainder on the next Next call.
+func TestReaderNext(t *testing.T) {
+	f, err := os.Open("testdata/sample.warc")
+	if err != nil {
+		t.Fatalf("open fixture: %v", err)
+	}
+	defer f.Close()
+
+	r := NewReader(f)
+
+	rec1, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next() #1: %v", err)
+	}
+	if rec1.Version != "WARC/1.1" {
+		t.Errorf("rec1.Version = %q, want WARC/1.1", rec1.Version)
+	}
+	if got := rec1.Header.Get("WARC-Type"); got != "response" {
+		t.Errorf("rec1 WARC-Type = %q, want response", got)
+	}
+	if got := rec1.Header.Get("WARC-Target-URI"); got != "http://example.com/" {
+		t.Errorf("rec1 WARC-Target-URI = %q, want http://example.com/", got)
+	}
+
+	// Intentionally don't fully drain rec1.Body before calling Next again;
+	// Next is documented to discard the remainder itself.
+	rec2, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next() #2: %v", err)
+	}
+	if got := rec2.Header.Get("WARC-Type"); got != "metadata" {
+		t.Errorf("rec2 WARC-Type = %q, want metadata", got)
+	}
+	body2, err := io.ReadAll(rec2.Body)
+	if err != nil {
+		t.Fatalf("read rec2 body: %v", err)
+	}
+	if want := "some: metadata\r\nfetchTimeMs: 42\r\n"; string(body2) != want {
+		t.Errorf("rec2 body = %q, want %q", body2, want)
+	}
+
+	if _, err := r.Next(); err != io.EOF {
+		t.Fatalf("Next() #3 = %v, want io.EOF", err)
+	}
+}
+
+// TestNewGzipReader checks that the gzip multistream variant of the same
+// fixture (testdata/sample.warc.gz, one independently-gzipped member per
+// record, as CommonCrawl actually distributes them) parses identically.
+func TestNewGzipReader(t *testing.T) {
+	f, err := os.Open("testdata/sample.warc.gz")
+	if err != nil {
+		t.Fatalf("open fixture: %v", err)
+	}
+	defer f.Close()
+
+	r, err := NewGzipReader(f)
+	if err != nil {
+		t.Fatalf("NewGzipReader: %v", err)
+	}
+
+	var recordIDs []string
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		recordIDs = append(recordIDs, rec.Header.Get("WARC-Record-ID"))
+	}
+
+	want := []string{
+		"<urn:uuid:11111111-1111-1111-1111-111111111111>",
+		"<urn:uuid:22222222-2222-2222-2222-222222222222>",
+	}
+	if len(recordIDs) != len(want) {
+		t.Fatalf("got %d records, want %d: %v", len(recordIDs), len(want), recordIDs)
+	}
+	for i, id := range want {
+		if recordIDs[i] != id {
+			t.Errorf("record #%d ID = %q, want %q", i, recordIDs[i], id)
+		}
+	}
+}
+
+// TestSplitHTTPPayload checks the embedded-HTTP-response parsing used for
+// WARC "response"/"request" records, against the first record of the same
+// fixture.
+func TestSplitHTTPPayload(t *testing.T) {
+	f, err := os.Open("testdata/sample.warc")
+	if err != nil {
+		t.Fatalf("open fixture: %v", err)
+	}
+	defer f.Close()
+
+	r := NewReader(f)
+	rec, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+
+	startLine, header, payload, err := SplitHTTPPayload(rec.Body)
+	if err != nil {
+		t.Fatalf("SplitHTTPPayload: %v", err)
+	}
+	if startLine != "HTTP/1.1 200 OK" {
+		t.Errorf("startLine = %q, want %q", startLine, "HTTP/1.1 200 OK")
+	}
+	if got := header.Get("Content-Type"); got != "text/plain" {
+		t.Errorf("Content-Type header = %q, want text/plain", got)
+	}
+
+	body, err := io.ReadAll(payload)
+	if err != nil {
+		t.Fatalf("read payload: %v", err)
+	}
+	if string(body) != "Hello, world!" {
+		t.Errorf("payload = %q, want %q", body, "Hello, world!")
+	}
+}
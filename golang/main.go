@@ -1,32 +1,102 @@
 package main
 
 import (
-	"bufio"
-	"compress/gzip"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
-	"log"
-	"net/http"
+	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime/pprof"
 	"strings"
+	"syscall"
+	"time"
+
+	"commoncrawl-utilities/internal/fetch"
+	"commoncrawl-utilities/internal/obs"
+	"commoncrawl-utilities/internal/pipeline"
+	"commoncrawl-utilities/internal/sink"
+	"commoncrawl-utilities/internal/warc"
 )
 
 type Config struct {
-	ReservedWords []string `json:"reserved_words"`
-	RecordStart   string   `json:"record_start"`
+	// ReservedWords lists which WARC header fields are projected into the
+	// output record; any header not listed here is dropped.
+	ReservedWords []string              `json:"reserved_words"`
+	Sinks         map[string]SinkConfig `json:"sinks"`
+	API           APIConfig             `json:"api"`
+	Fetch         FetchConfig           `json:"fetch"`
 }
 
-func startMemoryProfile() *os.File {
+// APIConfig holds knobs unrelated to any single subsystem.
+type APIConfig struct {
+	// DisableRemoteDownload short-circuits every fetch, for locked-down
+	// deployments that only process pre-staged files via -file.
+	DisableRemoteDownload bool `json:"disable_remote_download"`
+}
+
+// FetchConfig configures the SSRF-hardened downloader.
+type FetchConfig struct {
+	// AllowedHosts overrides fetch.DefaultAllowedHosts when non-empty.
+	AllowedHosts []string `json:"allowed_hosts"`
+}
+
+// SinkConfig tunes a single sink named under the "sinks" block of
+// config.json (e.g. "sinks": {"s3": {"part_max_bytes": ...}}). The key
+// matched is the sink's scheme: "file", "s3", "gs", or "stdout".
+type SinkConfig struct {
+	PartMaxBytes int64  `json:"part_max_bytes"`
+	PartMaxAge   string `json:"part_max_age"`
+	Compression  string `json:"compression"`
+}
+
+// sinkOptions resolves the Options for scheme from the config's "sinks"
+// block, parsing PartMaxAge and falling back to sink.Options defaults when
+// the scheme has no entry.
+func sinkOptions(config Config, scheme string, logger *slog.Logger) sink.Options {
+	sc, ok := config.Sinks[scheme]
+	if !ok {
+		return sink.Options{}
+	}
+	var maxAge time.Duration
+	if sc.PartMaxAge != "" {
+		if d, err := time.ParseDuration(sc.PartMaxAge); err == nil {
+			maxAge = d
+		} else {
+			logger.Warn("sinkOptions: ignoring invalid part_max_age", "scheme", scheme, "part_max_age", sc.PartMaxAge, "error", err)
+		}
+	}
+	return sink.Options{
+		PartMaxBytes: sc.PartMaxBytes,
+		PartMaxAge:   maxAge,
+		Compression:  sc.Compression,
+	}
+}
+
+// fileSinkOptions layers the -rotate-bytes and -resume flags, plus the
+// shared metrics, on top of whatever config.json's "sinks" block set for
+// the "file" scheme, since -rotate-bytes/-resume only make sense for a
+// local-directory sink.
+func fileSinkOptions(config Config, rotateBytes int64, resume bool, logger *slog.Logger, metrics *obs.Metrics) sink.Options {
+	opts := sinkOptions(config, "file", logger)
+	if rotateBytes > 0 {
+		opts.PartMaxBytes = rotateBytes
+	}
+	opts.Resume = resume
+	opts.Metrics = metrics
+	return opts
+}
+
+func startMemoryProfile() (*os.File, error) {
 	memProfile, err := os.Create("mem_profile.pprof")
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	pprof.WriteHeapProfile(memProfile)
-	return memProfile
+	return memProfile, nil
 }
 
 func readConfig(configFilePath string) (Config, error) {
@@ -46,81 +116,194 @@ func readConfig(configFilePath string) (Config, error) {
 	return config, nil
 }
 
-// DownloadFile downloads a file from a URL and saves it to a local path.
-func downloadFile(url, localFilePath string) error {
+func main() {
+	os.Exit(run())
+}
 
-	response, err := http.Get(url)
-	if err != nil {
-		return err
-	}
-	defer response.Body.Close()
+// run holds everything main used to do directly, so that deferred cleanup
+// (closing the sink, the checkpoint, the memory profile) still executes on
+// a shutdown signal; returning an int lets main call os.Exit after those
+// defers have run, instead of os.Exit short-circuiting them itself.
+func run() int {
+	// Read the environment variable for debug mode
+	debugModeEnabled := os.Getenv("DEBUG_MODE") == "true"
 
-	if response.StatusCode != http.StatusOK {
-		return fmt.Errorf("Failed to download file. Status code: %d", response.StatusCode)
-	}
+	// Parse command line arguments
+	url := flag.String("url", "", "URL of a single file to download and process")
+	manifest := flag.String("manifest", "", "path to a manifest (plain or .gz) of WARC/WAT/WET URLs, one per line")
+	file := flag.String("file", "", "path to an already-downloaded WARC/WAT/WET file to process directly, without fetching anything")
+	workers := flag.Int("workers", 4, "number of concurrent download/process workers")
+	sinkURI := flag.String("sink", "", "output sink: file://<dir> (default data/output), s3://bucket/prefix, gs://bucket/prefix, stdout, or mock")
+	allowPrivate := flag.Bool("allow-private", false, "allow downloads to resolve to private/loopback/link-local addresses")
+	maxBytes := flag.Int64("max-bytes", 5*1024*1024*1024, "maximum bytes to download per URL; 0 means unlimited")
+	checksums := flag.String("checksums", "", "path to a sidecar checksum manifest (sha256sum-format) to verify downloads against")
+	rotateBytes := flag.Int64("rotate-bytes", 0, "rotate a file-sink partition to a new output-NNNN.jsonl shard once it exceeds this many bytes; 0 disables rotation")
+	resume := flag.Bool("resume", false, "resume from existing *.jsonl.partial files left by a crashed run instead of discarding them")
+	metricsAddr := flag.String("metrics-addr", "", "if set, serve Prometheus metrics on /metrics and a liveness check on /healthz at this address (e.g. :9090)")
+	logFormat := flag.String("log-format", "text", "log output format: text or json")
+	logLevel := flag.String("log-level", "info", "minimum log level: debug, info, warn, or error")
+	flag.Parse()
 
-	file, err := os.Create(localFilePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
+	logger := obs.NewLogger(*logFormat, *logLevel, debugModeEnabled)
+	metrics := obs.NewMetrics()
 
-	_, err = io.Copy(file, response.Body)
-	if err != nil {
-		return err
+	if *metricsAddr != "" {
+		server := metrics.Serve(*metricsAddr, debugModeEnabled)
+		defer server.Close()
 	}
 
-	return nil
-}
-
-func main() {
-	// Read the environment variable for debug mode
-	debugModeEnabled := os.Getenv("DEBUG_MODE") == "true"
-
 	if debugModeEnabled {
-		memProfile := startMemoryProfile()
+		memProfile, err := startMemoryProfile()
+		if err != nil {
+			logger.Error("Failed to start memory profile", "error", err)
+			return 1
+		}
 		defer memProfile.Close()
 	}
-	// Parse command line arguments
-	url := flag.String("url", "", "URL of the file to download and process")
-	flag.Parse()
 
-	if *url == "" {
-		fmt.Println("Please provide a URL using the -url flag")
-		return
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+
+	// Read the config from config.json
+	configFilePath := filepath.Join("config", "config.json")
+	config, err := readConfig(configFilePath)
+	if err != nil {
+		logger.Error("Failed to read config file", "error", err)
+		return 1
 	}
+	logger.Debug("Loaded config", "config", config)
 
 	// Create data directory if it doesn't exist
 	dataDir := "data"
 	if err := os.MkdirAll(dataDir, os.ModePerm); err != nil {
-		log.Fatalf("Failed to create data directory: %v", err)
+		logger.Error("Failed to create data directory", "error", err)
+		return 1
 	}
 
-	// Read the config from config.json
-	configFilePath := filepath.Join("config", "config.json")
-	config, err := readConfig(configFilePath)
+	resolvedSinkURI := *sinkURI
+	if resolvedSinkURI == "" {
+		resolvedSinkURI = "file://" + filepath.Join(dataDir, "output")
+	}
+	scheme, _, _ := strings.Cut(resolvedSinkURI, "://")
+	if resolvedSinkURI == "stdout" {
+		scheme = "stdout"
+	}
+	opts := sinkOptions(config, scheme, logger)
+	if scheme == "file" {
+		opts = fileSinkOptions(config, *rotateBytes, *resume, logger, metrics)
+	}
+	recordSink, err := sink.New(resolvedSinkURI, opts)
 	if err != nil {
-		log.Fatalf("Failed to read config file: %v", err)
+		logger.Error("Failed to construct sink", "sink", resolvedSinkURI, "error", err)
+		return 1
 	}
-	// Print the value of the config for debugging purposes
-	if debugModeEnabled {
-		log.Printf("Config: %+v\n", config)
+	defer func() {
+		if err := recordSink.Close(); err != nil {
+			logger.Error("Failed to close sink cleanly", "error", err)
+		}
+	}()
+
+	if *file != "" {
+		if *url != "" || *manifest != "" {
+			logger.Error("specify either -file or -url/-manifest, not both")
+			return 1
+		}
+		if err := processFile(ctx, *file, config, recordSink, logger, metrics); err != nil {
+			logger.Error("Failed to process file", "file", *file, "error", err)
+			return 1
+		}
+		return 0
+	}
+
+	if config.API.DisableRemoteDownload {
+		logger.Error("api.disable_remote_download is set in config.json; process pre-staged files with -file instead of -url/-manifest")
+		return 1
+	}
+
+	urls, err := resolveURLs(*url, *manifest)
+	if err != nil {
+		fmt.Println(err)
+		return 1
 	}
 
-	// Download the file
-	localFilePath := filepath.Join(dataDir, filepath.Base(*url))
-	if err := downloadFile(*url, localFilePath); err != nil {
-		log.Fatalf("Failed to download file: %v", err)
+	var checksumManifest fetch.ChecksumManifest
+	if *checksums != "" {
+		checksumManifest, err = fetch.LoadChecksumManifest(*checksums)
+		if err != nil {
+			logger.Error("Failed to load checksum manifest", "path", *checksums, "error", err)
+			return 1
+		}
 	}
-	defer os.Remove(localFilePath)
 
-	baseOutputFolder := filepath.Join(dataDir, "output")
-	os.RemoveAll(baseOutputFolder) // Remove the existing output folder
-	// Process the file
-	if err := processFile(localFilePath, config, baseOutputFolder); err != nil {
-		log.Fatalf("Failed to process file: %v", err)
+	checkpoint, err := pipeline.LoadCheckpoint(filepath.Join(dataDir, "checkpoint.jsonl"))
+	if err != nil {
+		logger.Error("Failed to load checkpoint", "error", err)
+		return 1
+	}
+	defer checkpoint.Close()
+
+	downloader := fetch.New(fetch.Config{
+		AllowedHosts: config.Fetch.AllowedHosts,
+		AllowPrivate: *allowPrivate,
+		MaxBytes:     *maxBytes,
+		Metrics:      metrics,
+		Logger:       logger,
+	})
+
+	start := time.Now()
+	var totalBytes int64
+	p := pipeline.New(*workers, checkpoint, logger)
+	runErr := p.Run(ctx, urls, func(ctx context.Context, u string) (int64, string, error) {
+		localFilePath := filepath.Join(dataDir, filepath.Base(u))
+		defer os.Remove(localFilePath)
+		if err := downloader.Download(ctx, u, localFilePath, checksumManifest[u]); err != nil {
+			return 0, "", fmt.Errorf("download %s: %w", u, err)
+		}
+
+		sum, err := fetch.SHA256File(localFilePath)
+		if err != nil {
+			return 0, "", fmt.Errorf("checksum %s: %w", u, err)
+		}
+		info, err := os.Stat(localFilePath)
+		if err != nil {
+			return 0, "", err
+		}
+
+		if err := processFile(ctx, localFilePath, config, recordSink, logger, metrics); err != nil {
+			return info.Size(), sum, fmt.Errorf("process %s: %w", u, err)
+		}
+		totalBytes += info.Size()
+		return info.Size(), sum, nil
+	})
+
+	elapsed := time.Since(start)
+	throughput := float64(totalBytes) / elapsed.Seconds() / (1024 * 1024)
+	logger.Info("Summary", "urls", len(urls), "bytes", totalBytes, "elapsed_ms", elapsed.Round(time.Millisecond).Milliseconds(), "mib_per_sec", throughput)
+
+	if ctx.Err() != nil {
+		logger.Warn("Shutting down on signal", "error", ctx.Err())
+		return 1
 	}
+	if runErr != nil {
+		logger.Error("Pipeline finished with errors", "error", runErr)
+		return 1
+	}
+	return 0
+}
 
+// resolveURLs builds the list of URLs to process from the -url and
+// -manifest flags. Exactly one of them must be set.
+func resolveURLs(url, manifestPath string) ([]string, error) {
+	if url != "" && manifestPath != "" {
+		return nil, fmt.Errorf("specify either -url or -manifest, not both")
+	}
+	if url != "" {
+		return []string{url}, nil
+	}
+	if manifestPath != "" {
+		return pipeline.LoadManifest(manifestPath)
+	}
+	return nil, fmt.Errorf("please provide a URL using -url or a manifest using -manifest, or a local file using -file")
 }
 
 // isReservedWord checks if a key exists in ReservedWords
@@ -133,96 +316,66 @@ func isReservedWord(config Config, key string) bool {
 	return false
 }
 
-func processFile(filePath string, config Config, outputDir string) error {
+// processFile parses filePath as a gzip-compressed WARC/WAT/WET file and
+// writes one flattened record per WARC record to out: the header fields
+// listed in config.ReservedWords, plus a "data" field holding the record's
+// payload (the body past the embedded HTTP headers for request/response
+// records, or the raw block for WAT/WET metadata/conversion records).
+func processFile(ctx context.Context, filePath string, config Config, out sink.RecordSink, logger *slog.Logger, metrics *obs.Metrics) error {
 	inFile, err := os.Open(filePath)
 	if err != nil {
 		return err
 	}
 	defer inFile.Close()
 
-	gzipReader, err := gzip.NewReader(inFile)
+	reader, err := warc.NewGzipReader(inFile)
 	if err != nil {
 		return err
 	}
-	defer gzipReader.Close()
-
-	reader := bufio.NewReader(gzipReader)
-	record := make(map[string]string)
-	jsonlFiles := make(map[string]*os.File)
 
 	for {
-		line, err := reader.ReadString('\n')
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		parseStart := time.Now()
+		rec, err := reader.Next()
 		if err != nil {
 			if err == io.EOF {
-				break // Reached end of file
+				break
 			}
-			return err
+			return fmt.Errorf("processFile: %s: %w", filePath, err)
 		}
 
-		if strings.TrimSpace(line) != config.RecordStart {
-			parts := strings.Split(line, ":")
-			key := parts[0]
-			if len(parts) >= 2 && isReservedWord(config, key) {
-				value := strings.TrimSpace(strings.Join(parts[1:], ":"))
-				record[key] = value
-				//log.Printf("isReservedWord: %+v\n", strings.TrimSpace(line))
-			} else {
-				//log.Printf("Not isReservedWord: %+v\n", strings.TrimSpace(line))
-				//record["data"] += line + "\n"
-				record["data"] += line
+		outRecord := make(map[string]string, len(rec.Header)+1)
+		for key, values := range rec.Header {
+			if isReservedWord(config, key) {
+				outRecord[key] = strings.Join(values, ", ")
 			}
-		} else {
-			//log.Printf("Line: %+v; PreviousRecord: %+v\n", strings.TrimSpace(line), record)
-			if err := writeRecordToJSONL(record, outputDir, jsonlFiles); err != nil {
-				return err
-			}
-
-			// Clear the record for the next one
-			record = make(map[string]string)
 		}
-	}
 
-	log.Printf("LastLine; PreviousRecord: %+v\n", record)
-
-	if err := writeRecordToJSONL(record, outputDir, jsonlFiles); err != nil {
-		return err
-	}
-
-	// Close all JSONL files
-	for _, jsonlFile := range jsonlFiles {
-		jsonlFile.Close()
-	}
-
-	return nil
-}
-
-func writeRecordToJSONL(record map[string]string, outputDir string, jsonlFiles map[string]*os.File) error {
-	language := strings.ReplaceAll(record["WARC-Identified-Content-Language"], ",", "_")
-
-	if language == "" {
-		language = "eng" // Default language if not specified
-	}
-
-	outputFolder := filepath.Join(outputDir, language)
-	os.MkdirAll(outputFolder, os.ModePerm)
-	outputFile := filepath.Join(outputFolder, "output.jsonl")
+		payload := rec.Body
+		if warcType := rec.Header.Get("WARC-Type"); warcType == "request" || warcType == "response" {
+			_, _, body, err := warc.SplitHTTPPayload(rec.Body)
+			if err != nil {
+				return fmt.Errorf("processFile: %s: %w", filePath, err)
+			}
+			payload = body
+		}
 
-	// Open or create the JSONL file
-	jsonlFile, exists := jsonlFiles[outputFile]
-	if !exists {
-		var err error
-		jsonlFile, err = os.OpenFile(outputFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644) // Set permissions to 0644
+		data, err := io.ReadAll(payload)
 		if err != nil {
-			return err
+			return fmt.Errorf("processFile: %s: read record body: %w", filePath, err)
+		}
+		outRecord["data"] = string(data)
+		if metrics != nil {
+			metrics.ParseDuration.Observe(time.Since(parseStart).Seconds())
 		}
-		jsonlFiles[outputFile] = jsonlFile
-	}
 
-	// Write the record to the JSONL file
-	jsonEncoder := json.NewEncoder(jsonlFile)
-	jsonEncoder.SetEscapeHTML(false) // Disable HTML escaping
-	if err := jsonEncoder.Encode(record); err != nil {
-		return err
+		logger.Debug("processFile: writing record", "record_id", rec.Header.Get("WARC-Record-ID"), "lang", outRecord["WARC-Identified-Content-Language"], "bytes", len(data))
+		if err := out.Write(ctx, outRecord); err != nil {
+			return err
+		}
 	}
 
 	return nil